@@ -0,0 +1,157 @@
+// Package state implements a client for Atlas's remote state storage API,
+// the same endpoint Terraform uses to store and retrieve remote state.
+package state
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	atlas "github.com/hashicorp/atlas-go/v1"
+)
+
+// Payload is a wrapper around a piece of remote state data along with the
+// checksum the server attached to it, allowing callers to detect whether
+// their local copy is stale before writing a new one back.
+type Payload struct {
+	MD5  []byte
+	Data []byte
+}
+
+// ErrStateConflict is returned by Put when the server rejects a write
+// because the state has been modified since it was last read. MD5 is the
+// checksum of the state currently stored on the server, so callers can
+// fetch it and attempt a merge or a forced overwrite.
+type ErrStateConflict struct {
+	MD5 []byte
+}
+
+func (e *ErrStateConflict) Error() string {
+	return fmt.Sprintf("state: conflict, server state has MD5 %x", e.MD5)
+}
+
+// StateClient is a client for a single piece of remote state, identified by
+// the user and name it is stored under. RunID, if set, is attached to PUT
+// requests so that Atlas can associate the write with a specific run.
+type StateClient struct {
+	Client *atlas.Client
+	User   string
+	Name   string
+	RunID  string
+}
+
+// path returns the API path for this client's state.
+func (c *StateClient) path() string {
+	return fmt.Sprintf("/api/v1/terraform/state/%s/%s", c.User, c.Name)
+}
+
+// Get fetches the current state payload from Atlas. It returns a nil
+// Payload, with no error, if no state has been stored yet.
+func (c *StateClient) Get() (*Payload, error) {
+	request, err := c.Client.Request("GET", c.path(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		data, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		md5, err := decodeContentMD5(response.Header.Get("Content-MD5"))
+		if err != nil {
+			return nil, err
+		}
+
+		return &Payload{MD5: md5, Data: data}, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("state: unexpected response fetching state: %s", response.Status)
+	}
+}
+
+// Put writes the given state to Atlas. If the state stored on the server
+// has moved on since it was last read, Put returns an *ErrStateConflict.
+func (c *StateClient) Put(state []byte) error {
+	sum := md5.Sum(state)
+	b64 := base64.StdEncoding.EncodeToString(sum[:])
+
+	ro := &atlas.RequestOptions{
+		Body:       bytes.NewReader(state),
+		BodyLength: int64(len(state)),
+		Headers: map[string]string{
+			"Content-MD5":  b64,
+			"Content-Type": "application/json",
+		},
+	}
+	if c.RunID != "" {
+		ro.Params = map[string]string{"atlas_run_id": c.RunID}
+	}
+
+	request, err := c.Client.Request("PUT", c.path(), ro)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusConflict:
+		md5, err := decodeContentMD5(response.Header.Get("Content-MD5"))
+		if err != nil {
+			return err
+		}
+		return &ErrStateConflict{MD5: md5}
+	default:
+		return fmt.Errorf("state: unexpected response storing state: %s", response.Status)
+	}
+}
+
+// Delete removes the state from Atlas.
+func (c *StateClient) Delete() error {
+	request, err := c.Client.Request("DELETE", c.path(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	default:
+		return fmt.Errorf("state: unexpected response deleting state: %s", response.Status)
+	}
+}
+
+// decodeContentMD5 base64-decodes the value of a Content-MD5 header. It
+// returns nil, nil if the header is empty.
+func decodeContentMD5(header string) ([]byte, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	return base64.StdEncoding.DecodeString(header)
+}
@@ -0,0 +1,135 @@
+package state
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	atlas "github.com/hashicorp/atlas-go/v1"
+)
+
+func TestStateClient_GetPutRoundTrip(t *testing.T) {
+	data := []byte(`{"serial":1,"resources":[]}`)
+	sum := md5.Sum(data)
+	b64 := base64.StdEncoding.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-MD5", b64)
+			w.WriteHeader(http.StatusOK)
+			w.Write(data)
+		case http.MethodPut:
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(body, data) {
+				t.Fatalf("expected PUT body %q, got %q", data, body)
+			}
+			if got := r.Header.Get("Content-MD5"); got != b64 {
+				t.Fatalf("expected Content-MD5 header %q, got %q", b64, got)
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	client, err := atlas.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := &StateClient{Client: client, User: "user", Name: "name"}
+
+	payload, err := sc.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(payload.Data, data) {
+		t.Fatalf("expected data %q, got %q", data, payload.Data)
+	}
+	if !bytes.Equal(payload.MD5, sum[:]) {
+		t.Fatalf("expected MD5 %x, got %x", sum, payload.MD5)
+	}
+
+	if err := sc.Put(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStateClient_Get_notFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := atlas.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := &StateClient{Client: client, User: "user", Name: "name"}
+
+	payload, err := sc.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload != nil {
+		t.Fatalf("expected nil payload when no state exists, got %+v", payload)
+	}
+}
+
+func TestStateClient_Put_conflict(t *testing.T) {
+	serverSum := md5.Sum([]byte("state stored on the server"))
+	serverB64 := base64.StdEncoding.EncodeToString(serverSum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-MD5", serverB64)
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+
+	client, err := atlas.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := &StateClient{Client: client, User: "user", Name: "name"}
+
+	err = sc.Put([]byte("local state"))
+	if err == nil {
+		t.Fatal("expected a conflict error")
+	}
+
+	conflict, ok := err.(*ErrStateConflict)
+	if !ok {
+		t.Fatalf("expected *ErrStateConflict, got %T: %s", err, err)
+	}
+	if !bytes.Equal(conflict.MD5, serverSum[:]) {
+		t.Fatalf("expected conflict MD5 %x, got %x", serverSum, conflict.MD5)
+	}
+}
+
+func TestStateClient_Delete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Fatalf("expected DELETE, got %s", r.Method)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := atlas.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := &StateClient{Client: client, User: "user", Name: "name"}
+
+	if err := sc.Delete(); err != nil {
+		t.Fatal(err)
+	}
+}
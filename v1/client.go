@@ -1,22 +1,25 @@
 package atlas
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"strings"
+	"time"
 )
 
 const atlasURL = "https://atlas.hashicorp.com"
 
-// If this is set to true, verbose debug data will be output
+// Debug, if set to true when a Client is created, causes that Client's
+// default Logger to emit debug output.
+//
+// Deprecated: set Client.Logger to a Logger configured the way you want,
+// and Client.Trace to enable wire-level tracing, instead.
 var Debug = false
 
 // ErrAuth is the error returned if a 401 is returned by an API request.
@@ -25,6 +28,10 @@ var ErrAuth = errors.New("authentication failed")
 // ErrNotFound is the error returned if a 404 is returned by an API request.
 var ErrNotFound = errors.New("resource not found")
 
+// errBodyTooLargeToBuffer is returned when a request body that isn't an
+// io.ReadSeeker is too large to buffer into memory for retries.
+var errBodyTooLargeToBuffer = errors.New("client: request body too large to buffer for retries; use an io.ReadSeeker")
+
 // RailsError represents an error that was returned from the Rails server.
 type RailsError struct {
 	Errors map[string][]string `json:"errors"`
@@ -52,8 +59,53 @@ type Client struct {
 	// Token is the Atlas authentication token
 	Token string
 
+	// Username and Password, if set, are sent as HTTP Basic auth
+	// credentials on every request. This is orthogonal to Token; it's
+	// meant for proxies or self-hosted Atlas installs that sit behind
+	// basic auth in front of the API.
+	Username string
+	Password string
+
+	// TokenInQuery, if true, sends Token as the "access_token" query
+	// string parameter instead of the X-Atlas-Token header. This exists
+	// only for backward compatibility with servers that don't understand
+	// the header yet; the query string leaks the token into server and
+	// proxy access logs, so header-based auth is the default.
+	TokenInQuery bool
+
 	// HTTPClient is the underlying http client with which to make requests.
 	HTTPClient *http.Client
+
+	// RetryMax is the maximum number of retry attempts for a request that
+	// fails with a connection error, a 429, or a 5xx response.
+	RetryMax int
+
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff applied
+	// between retry attempts; jitter can take a wait up to 25% below
+	// RetryWaitMin.
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+
+	// CheckRetry, if set, overrides the default retry policy. It is
+	// called after every request attempt with the response (nil on a
+	// connection error) and the error returned by HTTPClient.Do.
+	CheckRetry func(*http.Response, error) (bool, error)
+
+	// TLSConfig configures the TLS settings used to connect to Atlas. It
+	// is read once, by the init() that NewClient runs; changing it
+	// afterwards does not change the transport of HTTPClient.
+	TLSConfig *TLSConfig
+
+	// Logger receives diagnostic output. It defaults to a logger backed
+	// by the standard library's log package, gated by the deprecated
+	// Debug var.
+	Logger Logger
+
+	// Trace, if true, logs the full request and response for every API
+	// call via Logger.Debugf, with the Authorization and X-Atlas-Token
+	// headers and any access_token query parameter redacted. It is off by
+	// default since it logs full request/response bodies.
+	Trace bool
 }
 
 // DefaultClient returns a client that connects to the Atlas API.
@@ -71,7 +123,19 @@ func DefaultClient() *Client {
 // http.DefaultClient, but this can be changed programatically by setting
 // client.HTTPClient. The user can also programtically set the URL as a
 // *url.URL.
+//
+// The URL may embed HTTP Basic auth credentials (user:pass@host), which are
+// sent as an Authorization header on every request. The ATLAS_ADDRESS,
+// ATLAS_USERNAME, and ATLAS_PASSWORD environment variables, if set, take
+// precedence over urlString and any credentials embedded in it; setting
+// just ATLAS_USERNAME or ATLAS_PASSWORD overrides only that half of the
+// pair. Auth precedence, highest first, is: env vars, then URL userinfo,
+// then a token set by calling Login(), then the ATLAS_TOKEN env var.
 func NewClient(urlString string) (*Client, error) {
+	if v := os.Getenv("ATLAS_ADDRESS"); v != "" {
+		urlString = v
+	}
+
 	if len(urlString) == 0 {
 		return nil, fmt.Errorf("client: missing url")
 	}
@@ -81,9 +145,27 @@ func NewClient(urlString string) (*Client, error) {
 		return nil, err
 	}
 
+	var username, password string
+	if parsedURL.User != nil {
+		username = parsedURL.User.Username()
+		password, _ = parsedURL.User.Password()
+	}
+	if v := os.Getenv("ATLAS_USERNAME"); v != "" {
+		username = v
+	}
+	if v := os.Getenv("ATLAS_PASSWORD"); v != "" {
+		password = v
+	}
+
+	// Strip the userinfo so it isn't echoed back in logs or error
+	// messages; it's carried on the Client instead.
+	parsedURL.User = nil
+
 	client := &Client{
-		URL:   parsedURL,
-		Token: os.Getenv("ATLAS_TOKEN"),
+		URL:      parsedURL,
+		Token:    os.Getenv("ATLAS_TOKEN"),
+		Username: username,
+		Password: password,
 	}
 
 	if err := client.init(); err != nil {
@@ -127,14 +209,14 @@ func (c *Client) Login(username, password string) (string, error) {
 	}
 
 	// Make the request
-	response, err := checkResp(c.HTTPClient.Do(request))
+	response, err := c.checkResp(c.Do(request))
 	if err != nil {
 		return "", err
 	}
 
 	// Decode the body
 	var tokenResponse struct{ Token string }
-	if err := decodeJSON(response, &tokenResponse); err != nil {
+	if err := c.decodeJSON(response, &tokenResponse); err != nil {
 		return "", nil
 	}
 
@@ -147,7 +229,25 @@ func (c *Client) Login(username, password string) (string, error) {
 
 // init() sets defaults on the client.
 func (c *Client) init() error {
-	c.HTTPClient = http.DefaultClient
+	if c.Logger == nil {
+		c.Logger = &defaultLogger{debug: Debug}
+	}
+
+	c.RetryMax = defaultRetryMax
+	c.RetryWaitMin = defaultRetryWaitMin
+	c.RetryWaitMax = defaultRetryWaitMax
+
+	if c.TLSConfig == nil {
+		c.TLSConfig = &TLSConfig{}
+	}
+	c.TLSConfig.loadEnv()
+
+	transport, err := c.configureTransport()
+	if err != nil {
+		return err
+	}
+	c.HTTPClient = &http.Client{Transport: transport}
+
 	return nil
 }
 
@@ -170,16 +270,37 @@ func (c *Client) Request(verb, spath string, ro *RequestOptions) (*http.Request,
 	u := *c.URL
 	u.Path = path.Join(c.URL.Path, spath)
 
-	// Add the token and other params
+	// Add the token, either as a header (the default) or, for backward
+	// compatibility, as a query string parameter.
 	if c.Token != "" {
-		if ro.Params == nil {
-			ro.Params = make(map[string]string)
+		if c.TokenInQuery {
+			if ro.Params == nil {
+				ro.Params = make(map[string]string)
+			}
+
+			ro.Params["access_token"] = c.Token
+		} else {
+			if ro.Headers == nil {
+				ro.Headers = make(map[string]string)
+			}
+
+			ro.Headers["X-Atlas-Token"] = c.Token
 		}
+	}
+
+	request, err := c.rawRequest(verb, &u, ro)
+	if err != nil {
+		return nil, err
+	}
 
-		ro.Params["access_token"] = c.Token
+	// Add basic auth credentials, if any. This only applies to requests
+	// against c.URL; putFile's raw requests go to caller-supplied (often
+	// third-party, presigned) URLs and must never carry Atlas credentials.
+	if c.Username != "" || c.Password != "" {
+		request.SetBasicAuth(c.Username, c.Password)
 	}
 
-	return c.rawRequest(verb, &u, ro)
+	return request, nil
 }
 
 func (c *Client) putFile(rawUrl string, r io.Reader, size int64) error {
@@ -196,7 +317,7 @@ func (c *Client) putFile(rawUrl string, r io.Reader, size int64) error {
 		return err
 	}
 
-	if _, err := checkResp(c.HTTPClient.Do(request)); err != nil {
+	if _, err := c.checkResp(c.Do(request)); err != nil {
 		return err
 	}
 
@@ -241,13 +362,16 @@ func (c *Client) rawRequest(verb string, u *url.URL, ro *RequestOptions) (*http.
 		request.ContentLength = ro.BodyLength
 	}
 
+	// Make sure the body can be replayed if Do needs to retry the request.
+	setReplayableBody(request, ro.Body)
+
 	return request, nil
 }
 
 // checkResp wraps http.Client.Do() and verifies that the request was
 // successful. A non-200 request returns an error formatted to included any
 // validation problems or otherwise.
-func checkResp(resp *http.Response, err error) (*http.Response, error) {
+func (c *Client) checkResp(resp *http.Response, err error) (*http.Response, error) {
 	// If the err is already there, there was an error higher
 	// up the chain, so just return that
 	if err != nil {
@@ -264,7 +388,7 @@ func checkResp(resp *http.Response, err error) (*http.Response, error) {
 	case 204:
 		return resp, nil
 	case 400:
-		return nil, parseErr(resp)
+		return nil, c.parseErr(resp)
 	case 401:
 		return nil, ErrAuth
 	case 404:
@@ -276,29 +400,23 @@ func checkResp(resp *http.Response, err error) (*http.Response, error) {
 
 // parseErr is used to take an error json response and return a single string
 // for use in error messages.
-func parseErr(resp *http.Response) error {
+func (c *Client) parseErr(resp *http.Response) error {
 	railsError := &RailsError{}
 
-	if err := decodeJSON(resp, &railsError); err != nil {
+	if err := c.decodeJSON(resp, &railsError); err != nil {
 		return fmt.Errorf("Error parsing error body: %s", err)
 	}
 
 	return railsError
 }
 
-// decodeJSON is used to JSON decode a body into an interface.
-func decodeJSON(resp *http.Response, out interface{}) error {
+// decodeJSON is used to JSON decode a body into an interface. It never logs
+// the decoded body: response bodies (e.g. Login's, which is exactly
+// {"Token": "..."}) can carry secrets, and redact() only knows how to strip
+// them from HTTP header lines and query strings, not arbitrary JSON. Trace
+// remains the supported way to inspect traffic; it dumps the raw wire body
+// instead, which is still subject to that same header/query redaction.
+func (c *Client) decodeJSON(resp *http.Response, out interface{}) error {
 	defer resp.Body.Close()
-
-	var r io.Reader = resp.Body
-	if Debug {
-		var buf bytes.Buffer
-		r = io.TeeReader(resp.Body, &buf)
-		defer func() {
-			log.Printf("[DEBUG] client: decoding: %s", buf.String())
-		}()
-	}
-
-	dec := json.NewDecoder(r)
-	return dec.Decode(out)
+	return json.NewDecoder(resp.Body).Decode(out)
 }
\ No newline at end of file
@@ -0,0 +1,94 @@
+package atlas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_Request_tokenHeader(t *testing.T) {
+	client, err := NewClient("https://atlas.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Token = "foobar"
+
+	request, err := client.Request("GET", "/api/v1/thing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := request.Header.Get("X-Atlas-Token"); v != "foobar" {
+		t.Fatalf("expected X-Atlas-Token header to be %q, got %q", "foobar", v)
+	}
+
+	if request.URL.RawQuery != "" {
+		t.Fatalf("expected query string to be clean, got %q", request.URL.RawQuery)
+	}
+}
+
+func TestClient_Request_tokenInQuery(t *testing.T) {
+	client, err := NewClient("https://atlas.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Token = "foobar"
+	client.TokenInQuery = true
+
+	request, err := client.Request("GET", "/api/v1/thing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := request.Header.Get("X-Atlas-Token"); v != "" {
+		t.Fatalf("expected no X-Atlas-Token header, got %q", v)
+	}
+
+	if v := request.URL.Query().Get("access_token"); v != "foobar" {
+		t.Fatalf("expected access_token query param to be %q, got %q", "foobar", v)
+	}
+}
+
+func TestClient_Request_basicAuth(t *testing.T) {
+	client, err := NewClient("https://atlas.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Username = "user"
+	client.Password = "pass"
+
+	request, err := client.Request("GET", "/api/v1/thing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	username, password, ok := request.BasicAuth()
+	if !ok || username != "user" || password != "pass" {
+		t.Fatalf("expected basic auth user:pass, got %q:%q (ok=%v)", username, password, ok)
+	}
+}
+
+func TestClient_putFile_doesNotLeakBasicAuthToThirdParty(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient("https://atlas.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Username = "user"
+	client.Password = "pass"
+
+	if err := client.putFile(server.URL, strings.NewReader("artifact"), 8); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotAuth != "" {
+		t.Fatalf("expected no Authorization header on a presigned-URL upload, got %q", gotAuth)
+	}
+}
@@ -0,0 +1,89 @@
+package atlas
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+)
+
+// Logger is the interface used by a Client to emit diagnostic output. Any
+// type with Debugf/Errorf methods satisfies it; *log.Logger does not, so it
+// has to be wrapped.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// defaultLogger is the Logger used when a Client doesn't set one. It wraps
+// the standard library logger and honors the deprecated Debug var so
+// existing callers keep working.
+type defaultLogger struct {
+	debug bool
+}
+
+func (l *defaultLogger) Debugf(format string, args ...interface{}) {
+	if l.debug {
+		log.Printf("[DEBUG] client: "+format, args...)
+	}
+}
+
+func (l *defaultLogger) Errorf(format string, args ...interface{}) {
+	log.Printf("[ERROR] client: "+format, args...)
+}
+
+var (
+	reSensitiveHeader = regexp.MustCompile(`(?mi)^(Authorization|X-Atlas-Token):.*$`)
+	reAccessToken     = regexp.MustCompile(`access_token=[^&\s"]+`)
+)
+
+// redact strips an HTTP wire dump of anything that could leak credentials:
+// the Authorization and X-Atlas-Token headers, and an access_token query
+// string parameter.
+func redact(dump []byte) []byte {
+	dump = reSensitiveHeader.ReplaceAll(dump, []byte("$1: REDACTED"))
+	dump = reAccessToken.ReplaceAll(dump, []byte("access_token=REDACTED"))
+	return dump
+}
+
+// isMultipart reports whether the given Content-Type header is a multipart
+// form, the encoding used for artifact uploads. We don't want to dump those
+// bodies to the log.
+func isMultipart(contentType string) bool {
+	return len(contentType) >= len("multipart/form-data") &&
+		contentType[:len("multipart/form-data")] == "multipart/form-data"
+}
+
+// traceRequest logs the full outgoing request when c.Trace is enabled,
+// passing it through redact and skipping the body for multipart uploads.
+// Other bodies are logged unredacted.
+func (c *Client) traceRequest(request *http.Request) {
+	if !c.Trace {
+		return
+	}
+
+	dump, err := httputil.DumpRequestOut(request, !isMultipart(request.Header.Get("Content-Type")))
+	if err != nil {
+		c.Logger.Errorf("failed to dump request: %s", err)
+		return
+	}
+
+	c.Logger.Debugf("request:\n%s", redact(dump))
+}
+
+// traceResponse logs the full response when c.Trace is enabled, passing it
+// through redact and skipping the body for multipart responses. Other
+// bodies are logged unredacted.
+func (c *Client) traceResponse(response *http.Response) {
+	if !c.Trace || response == nil {
+		return
+	}
+
+	dump, err := httputil.DumpResponse(response, !isMultipart(response.Header.Get("Content-Type")))
+	if err != nil {
+		c.Logger.Errorf("failed to dump response: %s", err)
+		return
+	}
+
+	c.Logger.Debugf("response:\n%s", redact(dump))
+}
@@ -0,0 +1,213 @@
+package atlas
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default retry settings used when a Client does not override them.
+const (
+	defaultRetryMax     = 4
+	defaultRetryWaitMin = 1 * time.Second
+	defaultRetryWaitMax = 30 * time.Second
+)
+
+// defaultMaxRetryBodyBuffer is the largest request body we will buffer into
+// memory in order to replay it across retry attempts. A larger body that is
+// not an io.ReadSeeker is sent once and not retried.
+const defaultMaxRetryBodyBuffer = 10 * 1024 * 1024
+
+// Do sends the given request, retrying on connection errors, 429s, and 5xxs
+// using exponential backoff with jitter. The backoff honors a Retry-After
+// header when the server sends one. Retry behavior is controlled by
+// c.RetryMax, c.RetryWaitMin, c.RetryWaitMax, and c.CheckRetry.
+func (c *Client) Do(request *http.Request) (*http.Response, error) {
+	checkRetry := c.CheckRetry
+	if checkRetry == nil {
+		checkRetry = defaultCheckRetry
+	}
+
+	var response *http.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		// request.GetBody is nil whenever the request has no body at
+		// all, so there's nothing to reset and the retry proceeds as
+		// normal. It's only non-nil when there was a body to replay; if
+		// replaying it fails (e.g. a streamed upload too large to have
+		// been buffered), stop retrying and return the prior result
+		// instead of treating that as a fatal error.
+		if attempt > 0 && request.GetBody != nil {
+			body, bodyErr := request.GetBody()
+			if bodyErr != nil {
+				break
+			}
+			request.Body = body
+		}
+
+		c.traceRequest(request)
+
+		response, err = c.HTTPClient.Do(request)
+
+		c.traceResponse(response)
+
+		shouldRetry, checkErr := checkRetry(response, err)
+		if checkErr != nil {
+			return response, checkErr
+		}
+
+		if !shouldRetry || attempt >= c.RetryMax {
+			break
+		}
+
+		if response != nil {
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		time.Sleep(c.backoff(attempt, response))
+	}
+
+	return response, err
+}
+
+// defaultCheckRetry is the default CheckRetry used by a Client: retry on
+// connection errors, 429 (too many requests), and any 5xx response.
+func defaultCheckRetry(resp *http.Response, err error) (bool, error) {
+	if err != nil {
+		return true, nil
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true, nil
+	}
+
+	if resp.StatusCode >= 500 {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// backoff computes the wait before the next retry attempt, honoring a
+// Retry-After header if the response provides one, and otherwise using
+// exponential backoff from RetryWaitMin, capped at RetryWaitMax, less up to
+// 25% jitter.
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return wait
+		}
+	}
+
+	mult := math.Pow(2, float64(attempt)) * float64(c.RetryWaitMin)
+	wait := time.Duration(mult)
+	if float64(wait) != mult || wait > c.RetryWaitMax {
+		wait = c.RetryWaitMax
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	// Subtract up to 25% jitter so that many clients backing off at once
+	// don't retry in lockstep.
+	jitterMax := int64(wait) / 4
+	if jitterMax <= 0 {
+		return wait
+	}
+	jitter := time.Duration(rand.Int63n(jitterMax))
+	return wait - jitter
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which per RFC
+// 7231 is either a number of seconds or an HTTP-date.
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+// setReplayableBody arranges for request to be replayed across retry
+// attempts. If body implements io.ReadSeeker, it's rewound and reused
+// directly. Otherwise, it's wrapped in a bufferingBody that tees the
+// stream into memory (up to defaultMaxRetryBodyBuffer) as the first
+// attempt reads it, so a retry can be attempted without ever blocking or
+// failing the first send: a body that turns out to be too large to have
+// been buffered simply won't be retried (see bufferingBody.GetBody).
+func setReplayableBody(request *http.Request, body io.Reader) {
+	if body == nil || request.GetBody != nil {
+		return
+	}
+
+	if rs, ok := body.(io.ReadSeeker); ok {
+		request.GetBody = func() (io.ReadCloser, error) {
+			if _, err := rs.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(rs), nil
+		}
+		return
+	}
+
+	bb := &bufferingBody{r: body, limit: defaultMaxRetryBodyBuffer}
+	request.Body = bb
+	request.GetBody = bb.GetBody
+}
+
+// bufferingBody wraps a non-seekable request body, copying bytes into an
+// in-memory buffer as they're read by the first attempt. If the body
+// turns out to be larger than limit, the buffer is dropped and GetBody
+// reports the body can't be replayed -- it does not block or fail the
+// read that's already in flight.
+type bufferingBody struct {
+	r          io.Reader
+	buf        bytes.Buffer
+	limit      int64
+	overflowed bool
+}
+
+func (b *bufferingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if n > 0 && !b.overflowed {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.overflowed = true
+			b.buf.Reset()
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+func (b *bufferingBody) Close() error {
+	if c, ok := b.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// GetBody returns a fresh reader over the buffered bytes, or
+// errBodyTooLargeToBuffer if the body exceeded limit and wasn't fully
+// buffered.
+func (b *bufferingBody) GetBody() (io.ReadCloser, error) {
+	if b.overflowed {
+		return nil, errBodyTooLargeToBuffer
+	}
+	return ioutil.NopCloser(bytes.NewReader(b.buf.Bytes())), nil
+}
@@ -0,0 +1,114 @@
+package atlas
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Do_retriesRequestWithNilBody(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RetryWaitMin = time.Millisecond
+	client.RetryWaitMax = time.Millisecond
+
+	request, err := client.Request("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected eventual 200, got %d", response.StatusCode)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestClient_Do_doesNotRetryOversizedUnbufferableBody(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RetryWaitMin = time.Millisecond
+	client.RetryWaitMax = time.Millisecond
+
+	// A non-seekable body larger than the buffer limit must still succeed
+	// on the first attempt without error; it just won't be retried.
+	oversized := bytes.Repeat([]byte("a"), defaultMaxRetryBodyBuffer+1)
+	request, err := client.Request("PUT", "/", &RequestOptions{
+		Body:       &onceReader{data: oversized},
+		BodyLength: int64(len(oversized)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", response.StatusCode)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Fatalf("expected exactly 1 attempt (body too large to replay), got %d", got)
+	}
+}
+
+func TestClient_backoff_noPanicWhenWaitIsZero(t *testing.T) {
+	client, err := NewClient("https://atlas.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.RetryWaitMin = 0
+	client.RetryWaitMax = 0
+
+	if wait := client.backoff(0, nil); wait != 0 {
+		t.Fatalf("expected zero wait, got %s", wait)
+	}
+}
+
+// onceReader is a plain io.Reader (deliberately not an io.ReadSeeker) so
+// tests can exercise the non-seekable buffering path in setReplayableBody.
+type onceReader struct {
+	data []byte
+	off  int
+}
+
+func (r *onceReader) Read(p []byte) (int, error) {
+	if r.off >= len(r.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[r.off:])
+	r.off += n
+	return n, nil
+}
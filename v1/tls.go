@@ -0,0 +1,161 @@
+package atlas
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// TLSConfig configures the TLS settings used to connect to Atlas. It is
+// primarily useful for self-hosted Atlas/Terraform Enterprise installs that
+// sit behind a corporate PKI.
+type TLSConfig struct {
+	// CACert is the path to a PEM-encoded CA certificate bundle used, in
+	// addition to the system roots, to verify the server certificate.
+	CACert string
+
+	// CAPath is a directory containing PEM-encoded CA certificates; every
+	// *.pem file in it is added to the trusted roots.
+	CAPath string
+
+	// ClientCert and ClientKey are the paths to a PEM-encoded client
+	// certificate and key to present for mutual TLS.
+	ClientCert string
+	ClientKey  string
+
+	// Insecure disables server certificate verification. This should
+	// only ever be used for testing.
+	Insecure bool
+
+	// ServerName is used to verify the hostname on the server certificate,
+	// overriding the hostname from the Atlas URL.
+	ServerName string
+}
+
+// loadEnv fills in any unset fields from their corresponding environment
+// variables.
+func (t *TLSConfig) loadEnv() {
+	if t.CACert == "" {
+		t.CACert = os.Getenv("ATLAS_CAFILE")
+	}
+	if t.CAPath == "" {
+		t.CAPath = os.Getenv("ATLAS_CAPATH")
+	}
+	if t.ClientCert == "" {
+		t.ClientCert = os.Getenv("ATLAS_CLIENT_CERT")
+	}
+	if t.ClientKey == "" {
+		t.ClientKey = os.Getenv("ATLAS_CLIENT_KEY")
+	}
+	if t.ServerName == "" {
+		t.ServerName = os.Getenv("ATLAS_TLS_SERVER_NAME")
+	}
+	if !t.Insecure {
+		if v, err := strconv.ParseBool(os.Getenv("ATLAS_INSECURE")); err == nil {
+			t.Insecure = v
+		}
+	}
+}
+
+// defaultTransport returns an *http.Transport with the same sane,
+// connection-reusing defaults as hashicorp/go-cleanhttp's
+// DefaultTransport, hand-rolled here rather than imported so the client
+// has no dependency to resolve at build time (see retry.go, which takes
+// the same approach for go-retryablehttp).
+func defaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// appendCACerts loads the system root pool and appends the PEM bundle from
+// caFile, or every *.pem file under caPath, in the style of
+// hashicorp/go-rootcerts's ConfigureTLS. If neither caFile nor caPath is
+// set, it returns nil so the caller keeps using the system roots.
+func appendCACerts(caFile, caPath string) (*x509.CertPool, error) {
+	if caFile == "" && caPath == "" {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("client: failed to read CACert: %s", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client: no certificates found in CACert %s", caFile)
+		}
+	}
+
+	if caPath != "" {
+		files, err := ioutil.ReadDir(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("client: failed to read CAPath: %s", err)
+		}
+
+		for _, f := range files {
+			if f.IsDir() || filepath.Ext(f.Name()) != ".pem" {
+				continue
+			}
+
+			pem, err := ioutil.ReadFile(filepath.Join(caPath, f.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("client: failed to read CAPath file %s: %s", f.Name(), err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("client: no certificates found in %s", f.Name())
+			}
+		}
+	}
+
+	return pool, nil
+}
+
+// configureTransport builds an *http.Transport with cleanhttp-style
+// defaults and a tls.Config assembled from c.TLSConfig.
+func (c *Client) configureTransport() (*http.Transport, error) {
+	transport := defaultTransport()
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.TLSConfig.Insecure,
+		ServerName:         c.TLSConfig.ServerName,
+	}
+
+	if c.TLSConfig.ClientCert != "" && c.TLSConfig.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSConfig.ClientCert, c.TLSConfig.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	pool, err := appendCACerts(c.TLSConfig.CACert, c.TLSConfig.CAPath)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = pool
+
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
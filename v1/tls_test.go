@@ -0,0 +1,101 @@
+package atlas
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTLSTestServer() *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestClient_TLS_insecure(t *testing.T) {
+	server := newTLSTestServer()
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.TLSConfig = &TLSConfig{Insecure: true}
+	if err := client.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := client.Request("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Do(request); err != nil {
+		t.Fatalf("expected request to succeed with Insecure, got: %s", err)
+	}
+}
+
+func TestClient_TLS_untrustedWithoutCACert(t *testing.T) {
+	server := newTLSTestServer()
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A bad certificate is a connection error, which is otherwise retried;
+	// this test is about cert trust, not retry behavior, so disable
+	// retries to keep it fast.
+	client.RetryMax = 0
+
+	request, err := client.Request("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Do(request); err == nil {
+		t.Fatal("expected request to fail against an untrusted certificate")
+	}
+}
+
+func TestClient_TLS_caCert(t *testing.T) {
+	server := newTLSTestServer()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "atlas-go-tls-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+	if err := ioutil.WriteFile(caFile, pemBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.TLSConfig = &TLSConfig{CACert: caFile}
+	if err := client.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := client.Request("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Do(request); err != nil {
+		t.Fatalf("expected request to succeed with CACert, got: %s", err)
+	}
+}